@@ -0,0 +1,193 @@
+// auth.go abstracts away how jiratime authenticates to the Jira API.
+// Historically every call site hardcoded email+API-token basic auth;
+// this lets config select basic auth, a Data Center personal access
+// token, or full OAuth 2.0 (3LO) instead
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "path/filepath"
+
+    "golang.org/x/oauth2"
+)
+
+// Authenticator applies credentials to an outgoing request.  Every HTTP
+// call site uses conf.Auth.Apply(req) instead of calling SetBasicAuth
+// directly, so adding a new auth scheme never means touching call sites
+type Authenticator interface {
+    Apply(req *http.Request) error
+}
+
+// basicAuthenticator is the original email+API-token scheme
+type basicAuthenticator struct {
+    Username string
+    APIToken string
+}
+
+func (a *basicAuthenticator) Apply(req *http.Request) error {
+    req.SetBasicAuth(a.Username, a.APIToken)
+    return nil
+}
+
+// patAuthenticator is a Jira Data Center personal access token, sent as
+// a bearer token rather than basic auth
+type patAuthenticator struct {
+    Token string
+}
+
+func (a *patAuthenticator) Apply(req *http.Request) error {
+    req.Header.Set("Authorization", "Bearer "+a.Token)
+    return nil
+}
+
+// oauthAuthenticator is full OAuth 2.0 (3LO), refreshing and persisting
+// its token via oauth2.Config's TokenSource
+type oauthAuthenticator struct {
+    conf      *oauth2.Config
+    ctx       context.Context
+    src       oauth2.TokenSource
+    tokenFile string
+}
+
+func newOAuthAuthenticator(ctx context.Context, conf *oauth2.Config, tokenFile string) (
+        *oauthAuthenticator, error) {
+
+    token, err := loadOAuthToken(tokenFile)
+    if err != nil {
+        return nil, fmt.Errorf("run 'jiratime -login' first: %v", err)
+    }
+
+    return &oauthAuthenticator{
+        conf:      conf,
+        ctx:       ctx,
+        src:       conf.TokenSource(ctx, token),
+        tokenFile: tokenFile,
+    }, nil
+}
+
+func (a *oauthAuthenticator) Apply(req *http.Request) error {
+    token, err := a.src.Token()
+    if err != nil {
+        return err
+    }
+
+    // Persist the (possibly refreshed) token so the next run doesn't
+    // need a fresh user login
+    if err := saveOAuthToken(a.tokenFile, token); err != nil {
+        return err
+    }
+
+    token.SetAuthHeader(req)
+    return nil
+}
+
+// loadOAuthToken reads a previously persisted oauth2.Token as JSON
+func loadOAuthToken(tokenFile string) (*oauth2.Token, error) {
+    b, err := os.ReadFile(tokenFile)
+    if err != nil {
+        return nil, err
+    }
+
+    token := &oauth2.Token{}
+    if err := json.Unmarshal(b, token); err != nil {
+        return nil, err
+    }
+    return token, nil
+}
+
+// saveOAuthToken writes an oauth2.Token as JSON to tokenFile, creating
+// its parent directory if necessary
+func saveOAuthToken(tokenFile string, token *oauth2.Token) error {
+    if err := os.MkdirAll(filepath.Dir(tokenFile), 0700); err != nil {
+        return err
+    }
+
+    b, err := json.Marshal(token)
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(tokenFile, b, 0600)
+}
+
+// buildAuthenticator constructs the Authenticator selected by
+// conf.AuthType ("basic" is the default, for backwards compatibility
+// with configs that predate this field)
+func buildAuthenticator(ctx context.Context, c *config) (Authenticator, error) {
+
+    switch c.AuthType {
+    case "", "basic":
+        return &basicAuthenticator{Username: c.Username, APIToken: c.Userkey}, nil
+
+    case "pat":
+        return &patAuthenticator{Token: c.Userkey}, nil
+
+    case "oauth":
+        return newOAuthAuthenticator(ctx, oauthConfig(c), oauthTokenFile(c))
+
+    default:
+        return nil, fmt.Errorf("unknown AuthType: %s", c.AuthType)
+    }
+}
+
+// oauthConfig builds the oauth2.Config describing the Jira Cloud 3LO app
+func oauthConfig(c *config) *oauth2.Config {
+    return &oauth2.Config{
+        ClientID:     c.OAuthClientID,
+        ClientSecret: c.OAuthClientSecret,
+        RedirectURL:  c.OAuthRedirectURL,
+        Scopes:       []string{"read:jira-work", "offline_access"},
+        Endpoint: oauth2.Endpoint{
+            AuthURL:  "https://auth.atlassian.com/authorize",
+            TokenURL: "https://auth.atlassian.com/oauth/token",
+        },
+    }
+}
+
+// oauthTokenFile returns where the OAuth refresh token is persisted,
+// defaulting to a file under the user's config directory
+func oauthTokenFile(c *config) string {
+    if c.OAuthTokenFile != "" {
+        return c.OAuthTokenFile
+    }
+    confDir, err := os.UserConfigDir()
+    if err != nil {
+        confDir = "."
+    }
+    return filepath.Join(confDir, "jiratime-oauth-token.json")
+}
+
+// login runs the OAuth 2.0 authorization-code flow once, prompting the
+// user to visit a URL and paste back the resulting code, then persists
+// the refresh token so subsequent runs authenticate silently
+func login(ctx context.Context, c *config) error {
+    conf := oauthConfig(c)
+
+    verifier := oauth2.GenerateVerifier()
+    url := conf.AuthCodeURL("state", oauth2.AccessTypeOffline,
+            oauth2.S256ChallengeOption(verifier))
+
+    fmt.Printf("Visit the following URL to authorise jiratime, then paste " +
+            "the resulting code below:\n\n%s\n\nCode: ", url)
+
+    var code string
+    if _, err := fmt.Scanln(&code); err != nil {
+        return err
+    }
+
+    token, err := conf.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+    if err != nil {
+        return fmt.Errorf("failed to exchange code for token: %v", err)
+    }
+
+    if err := saveOAuthToken(oauthTokenFile(c), token); err != nil {
+        return fmt.Errorf("failed to save token: %v", err)
+    }
+
+    fmt.Println("Login successful.")
+    return nil
+}
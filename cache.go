@@ -0,0 +1,155 @@
+// cache.go adds an optional on-disk cache of issue worklogs so that
+// repeated runs over the same date range (e.g. "how much have I worked
+// this month so far") don't have to re-query every issue's worklogs on
+// every invocation: only issues whose "updated" timestamp has moved
+// since the last run are re-fetched
+package main
+
+import (
+    "compress/gzip"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "sync"
+)
+
+// cacheSchemaVersion is bumped whenever the on-disk cache format changes
+// in an incompatible way, so old caches are rebuilt rather than misread
+const cacheSchemaVersion = 1
+
+// cachedWorklog is the subset of a worklog entry worth persisting: enough
+// to rebuild a workItem without re-querying Jira
+type cachedWorklog struct {
+    Started          string `json:"started"`
+    TimeSpentSeconds uint   `json:"timeSpentSeconds"`
+}
+
+// cacheEntry holds everything cached for a single issue
+type cacheEntry struct {
+    Updated  string          `json:"updated"`
+    Worklogs []cachedWorklog `json:"worklogs"`
+}
+
+// cacheFile is the gzipped-JSON on-disk representation, scoped to a
+// single user's account ID
+type cacheFile struct {
+    SchemaVersion int                   `json:"schemaVersion"`
+    Issues        map[string]cacheEntry `json:"issues"`
+}
+
+// worklogCache wraps a cacheFile with the path it was loaded from and a
+// mutex, since getWork's workers read and write it concurrently
+type worklogCache struct {
+    mu      sync.Mutex
+    path    string
+    refresh bool
+    file    cacheFile
+    dirty   bool
+}
+
+// loadCache reads (or initialises) the cache file for a given user.  A
+// missing file, or one with a stale schema version, just starts empty -
+// this is a performance optimisation, not a source of truth
+func loadCache(cacheDir, accountID string, refresh bool) *worklogCache {
+
+    c := &worklogCache{
+        path:    filepath.Join(cacheDir, accountID+".json.gz"),
+        refresh: refresh,
+        file:    cacheFile{SchemaVersion: cacheSchemaVersion, Issues: map[string]cacheEntry{}},
+    }
+
+    if refresh {
+        return c
+    }
+
+    f, err := os.Open(c.path)
+    if err != nil {
+        return c
+    }
+    defer f.Close()
+
+    gz, err := gzip.NewReader(f)
+    if err != nil {
+        return c
+    }
+    defer gz.Close()
+
+    var loaded cacheFile
+    if err := json.NewDecoder(gz).Decode(&loaded); err != nil {
+        return c
+    }
+
+    if loaded.SchemaVersion == cacheSchemaVersion {
+        c.file = loaded
+        if c.file.Issues == nil {
+            c.file.Issues = map[string]cacheEntry{}
+        }
+    }
+
+    return c
+}
+
+// lookup returns the cached worklogs for issueID if the cache is fresh
+// (the issue's "updated" timestamp hasn't moved on) and -refresh wasn't
+// requested
+func (c *worklogCache) lookup(issueID, updated string) ([]cachedWorklog, bool) {
+    if c.refresh {
+        return nil, false
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    entry, ok := c.file.Issues[issueID]
+    if !ok || entry.Updated != updated {
+        return nil, false
+    }
+    return entry.Worklogs, true
+}
+
+// store records the worklogs fetched for issueID so a later run with the
+// same "updated" timestamp can skip the HTTP round trip
+func (c *worklogCache) store(issueID, updated string, worklogs []cachedWorklog) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.file.Issues[issueID] = cacheEntry{Updated: updated, Worklogs: worklogs}
+    c.dirty = true
+}
+
+// save writes the cache back to disk as gzipped JSON, if anything
+// changed, using a write-then-rename so a crash mid-write can't corrupt
+// the previous cache
+func (c *worklogCache) save() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if !c.dirty {
+        return nil
+    }
+
+    if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+        return err
+    }
+
+    tmp, err := os.CreateTemp(filepath.Dir(c.path), ".cache-*.tmp")
+    if err != nil {
+        return err
+    }
+    tmpName := tmp.Name()
+
+    gz := gzip.NewWriter(tmp)
+    err = json.NewEncoder(gz).Encode(c.file)
+    if cerr := gz.Close(); err == nil {
+        err = cerr
+    }
+    if cerr := tmp.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        os.Remove(tmpName)
+        return err
+    }
+
+    return os.Rename(tmpName, c.path)
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestCacheRoundTrip(t *testing.T) {
+    dir := t.TempDir()
+
+    c := loadCache(dir, "acct-1", false)
+    if _, hit := c.lookup("10042", "2024-03-12T09:00:00.000+0000"); hit {
+        t.Fatal("lookup hit on empty cache")
+    }
+
+    worklogs := []cachedWorklog{{Started: "2024-03-12T09:00:00.000+0000", TimeSpentSeconds: hour}}
+    c.store("10042", "2024-03-12T09:00:00.000+0000", worklogs)
+
+    if err := c.save(); err != nil {
+        t.Fatal(err)
+    }
+
+    reloaded := loadCache(dir, "acct-1", false)
+    got, hit := reloaded.lookup("10042", "2024-03-12T09:00:00.000+0000")
+    if !hit {
+        t.Fatal("lookup missed after save/reload")
+    }
+    if len(got) != 1 || got[0] != worklogs[0] {
+        t.Errorf("lookup returned %+v, want %+v", got, worklogs)
+    }
+
+    if _, hit := reloaded.lookup("10042", "2024-03-13T09:00:00.000+0000"); hit {
+        t.Error("lookup hit despite a changed Updated timestamp")
+    }
+}
+
+func TestCacheLookupRefresh(t *testing.T) {
+    dir := t.TempDir()
+
+    c := loadCache(dir, "acct-1", false)
+    c.store("10042", "2024-03-12T09:00:00.000+0000", []cachedWorklog{{Started: "x", TimeSpentSeconds: 1}})
+    if err := c.save(); err != nil {
+        t.Fatal(err)
+    }
+
+    refreshed := loadCache(dir, "acct-1", true)
+    if _, hit := refreshed.lookup("10042", "2024-03-12T09:00:00.000+0000"); hit {
+        t.Error("lookup hit with refresh requested, want always-miss")
+    }
+}
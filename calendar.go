@@ -0,0 +1,174 @@
+// calendar.go reconciles Jira worklogs against a Google Calendar so users
+// can spot days where meetings ate hours they never booked against a
+// ticket.  Calendar I/O is behind the calendarSource interface so tests
+// can substitute a double instead of talking to Google
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "sort"
+    "strings"
+    "time"
+)
+
+// calendarSource returns busy seconds per day (keyed the same way as
+// bucketKey's groupDay mode, "2006-01-02") for the given window
+type calendarSource interface {
+    BusyByDay(start, end time.Time, loc *time.Location) (map[string]uint, error)
+}
+
+// calResult is what a queryCalendar goroutine reports back: either the
+// busy-seconds-per-day map or the error that prevented computing it
+type calResult struct {
+    busy map[string]uint
+    err  error
+}
+
+// queryCalendar runs cal.BusyByDay in the background, in parallel with
+// getIDs/getWork, returning a channel that receives exactly one result.
+// Taking cal as a calendarSource rather than *googleCalendar is what lets
+// a test double stand in for the real Google Calendar API
+func queryCalendar(cal calendarSource, start, end time.Time, loc *time.Location) <-chan calResult {
+    busyCal := make(chan calResult, 1)
+
+    go func() {
+        busy, err := cal.BusyByDay(start, end, loc)
+        busyCal <- calResult{busy, err}
+    }()
+
+    return busyCal
+}
+
+// googleCalendar is a calendarSource backed by the Google Calendar v3 API
+type googleCalendar struct {
+    CalendarID string
+    TokenFile  string
+}
+
+// calendarEvent is the subset of a Google Calendar event resource we need
+type calendarEvent struct {
+    Start struct {
+        DateTime string `json:"dateTime"`
+        Date     string `json:"date"`
+    } `json:"start"`
+    End struct {
+        DateTime string `json:"dateTime"`
+    } `json:"end"`
+    Transparency string `json:"transparency"`
+}
+
+// readGoogleToken loads the bearer token written by the OAuth flow
+func readGoogleToken(filename string) (string, error) {
+    b, err := os.ReadFile(filename)
+    if err != nil {
+        return "", err
+    }
+    return strings.TrimSpace(string(b)), nil
+}
+
+// BusyByDay queries the events on CalendarID between start and end and
+// accumulates busy seconds per day, skipping all-day events and events
+// marked as "transparent" (i.e. not actually blocking the user's time)
+func (g *googleCalendar) BusyByDay(start, end time.Time, loc *time.Location) (
+        map[string]uint, error) {
+
+    token, err := readGoogleToken(g.TokenFile)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read %s: %v", g.TokenFile, err)
+    }
+
+    req, err := http.NewRequest("GET",
+            "https://www.googleapis.com/calendar/v3/calendars/"+
+                    url.PathEscape(g.CalendarID)+"/events", nil)
+    if err != nil {
+        return nil, err
+    }
+
+    p := url.Values{}
+    p.Set("timeMin", start.Format(time.RFC3339))
+    p.Set("timeMax", end.Format(time.RFC3339))
+    p.Set("singleEvents", "true")
+    p.Set("orderBy", "startTime")
+    req.URL.RawQuery = p.Encode()
+
+    req.Header.Add("Accept", "application/json")
+    req.Header.Add("Authorization", "Bearer "+token)
+
+    resp, err := httpc.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        return nil, fmt.Errorf("unexpected status querying calendar: %s",
+                resp.Status)
+    }
+
+    j := struct {
+        Items []calendarEvent `json:"items"`
+    }{}
+
+    if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
+        return nil, err
+    }
+
+    busy := map[string]uint{}
+    for _, ev := range j.Items {
+        if ev.Start.DateTime == "" {
+            // All-day event: has a Date but no DateTime
+            continue
+        }
+        if ev.Transparency == "transparent" {
+            continue
+        }
+
+        s, err := time.Parse(time.RFC3339, ev.Start.DateTime)
+        if err != nil {
+            continue
+        }
+        e, err := time.Parse(time.RFC3339, ev.End.DateTime)
+        if err != nil {
+            continue
+        }
+
+        day := s.In(loc).Format("2006-01-02")
+        busy[day] += uint(e.Sub(s).Seconds())
+    }
+
+    return busy, nil
+}
+
+// buildDiff merges per-day logged and busy seconds into a sorted slice of
+// dayDiff, so displayResults can show a "logged vs busy" column
+func buildDiff(logged, busy map[string]uint) []dayDiff {
+
+    days := map[string]bool{}
+    for d := range logged {
+        days[d] = true
+    }
+    for d := range busy {
+        days[d] = true
+    }
+
+    keys := make([]string, 0, len(days))
+    for d := range days {
+        keys = append(keys, d)
+    }
+    sort.Strings(keys)
+
+    diff := make([]dayDiff, 0, len(keys))
+    for _, d := range keys {
+        diff = append(diff, dayDiff{
+            Day:           d,
+            LoggedSeconds: logged[d],
+            BusySeconds:   busy[d],
+        })
+    }
+
+    return diff
+}
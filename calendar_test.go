@@ -0,0 +1,67 @@
+package main
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+// fakeCalendarSource is a calendarSource test double, standing in for
+// googleCalendar so queryCalendar can be exercised without talking to the
+// real Google Calendar API
+type fakeCalendarSource struct {
+    busy map[string]uint
+    err  error
+}
+
+func (f *fakeCalendarSource) BusyByDay(start, end time.Time, loc *time.Location) (map[string]uint, error) {
+    return f.busy, f.err
+}
+
+func TestQueryCalendar(t *testing.T) {
+    fake := &fakeCalendarSource{busy: map[string]uint{"2024-03-12": hour}}
+
+    busyCal := queryCalendar(fake, time.Time{}, time.Time{}, time.UTC)
+    result := <-busyCal
+
+    if result.err != nil {
+        t.Fatalf("queryCalendar returned unexpected error: %v", result.err)
+    }
+    if result.busy["2024-03-12"] != hour {
+        t.Errorf("queryCalendar busy = %+v, want 2024-03-12 = %d", result.busy, hour)
+    }
+}
+
+func TestQueryCalendarPropagatesError(t *testing.T) {
+    wantErr := errors.New("calendar unreachable")
+    fake := &fakeCalendarSource{err: wantErr}
+
+    busyCal := queryCalendar(fake, time.Time{}, time.Time{}, time.UTC)
+    result := <-busyCal
+
+    if result.err != wantErr {
+        t.Errorf("queryCalendar err = %v, want %v", result.err, wantErr)
+    }
+}
+
+func TestBuildDiff(t *testing.T) {
+    logged := map[string]uint{"2024-03-12": hour, "2024-03-13": 30 * minute}
+    busy := map[string]uint{"2024-03-13": hour, "2024-03-14": hour}
+
+    got := buildDiff(logged, busy)
+
+    want := []dayDiff{
+        {Day: "2024-03-12", LoggedSeconds: hour, BusySeconds: 0},
+        {Day: "2024-03-13", LoggedSeconds: 30 * minute, BusySeconds: hour},
+        {Day: "2024-03-14", LoggedSeconds: 0, BusySeconds: hour},
+    }
+
+    if len(got) != len(want) {
+        t.Fatalf("buildDiff returned %d entries, want %d", len(got), len(want))
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+        }
+    }
+}
@@ -0,0 +1,72 @@
+// group.go buckets the workItem triples emitted by getWork according to
+// the -group flag, so jiratime can answer "how much did I spend on
+// PROJ-X last week" instead of only ever printing one grand total
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Valid -group flag values
+const (
+    groupNone    = "none"
+    groupDay     = "day"
+    groupWeek    = "week"
+    groupMonth   = "month"
+    groupIssue   = "issue"
+    groupProject = "project"
+)
+
+// bucketKey computes the breakdown key a workItem falls into for a given
+// -group mode, converting its start time into loc first so day/week/month
+// boundaries fall where the user (not the Jira server) would expect them
+func bucketKey(group string, item workItem, loc *time.Location) string {
+
+    started := item.Started.In(loc)
+
+    switch group {
+    case groupDay:
+        return started.Format("2006-01-02")
+    case groupWeek:
+        year, week := started.ISOWeek()
+        return fmt.Sprintf("%d-W%02d", year, week)
+    case groupMonth:
+        return started.Format("2006-01")
+    case groupIssue:
+        return item.IssueKey
+    case groupProject:
+        if i := strings.LastIndex(item.IssueKey, "-"); i > 0 {
+            return item.IssueKey[:i]
+        }
+        return item.IssueKey
+    default:
+        return ""
+    }
+}
+
+// buildBreakdown turns a key->seconds map into a slice of bucketResult,
+// sorted by key so output is stable across runs
+func buildBreakdown(buckets map[string]uint) []bucketResult {
+
+    keys := make([]string, 0, len(buckets))
+    for k := range buckets {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+
+    breakdown := make([]bucketResult, 0, len(keys))
+    for _, k := range keys {
+        seconds := buckets[k]
+        breakdown = append(breakdown, bucketResult{
+            Key:     k,
+            Hours:   seconds / hour,
+            Minutes: (seconds % hour) / minute,
+            Seconds: seconds % minute,
+        })
+    }
+
+    return breakdown
+}
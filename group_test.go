@@ -0,0 +1,63 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+func TestBucketKey(t *testing.T) {
+    loc := time.UTC
+    started, err := time.Parse(time.RFC3339, "2024-03-12T10:00:00Z")
+    if err != nil {
+        t.Fatal(err)
+    }
+    item := workItem{IssueKey: "PROJ-42", Started: started, Seconds: 3600}
+
+    tests := []struct {
+        group string
+        want  string
+    }{
+        {groupDay, "2024-03-12"},
+        {groupWeek, "2024-W11"},
+        {groupMonth, "2024-03"},
+        {groupIssue, "PROJ-42"},
+        {groupProject, "PROJ"},
+        {groupNone, ""},
+    }
+
+    for _, tt := range tests {
+        if got := bucketKey(tt.group, item, loc); got != tt.want {
+            t.Errorf("bucketKey(%q) = %q, want %q", tt.group, got, tt.want)
+        }
+    }
+}
+
+func TestBucketKeyProjectWithoutDash(t *testing.T) {
+    item := workItem{IssueKey: "NODASH", Started: time.Now(), Seconds: 1}
+    if got := bucketKey(groupProject, item, time.UTC); got != "NODASH" {
+        t.Errorf("bucketKey(groupProject) = %q, want %q", got, "NODASH")
+    }
+}
+
+func TestBuildBreakdown(t *testing.T) {
+    buckets := map[string]uint{
+        "2024-03-13": 90*minute + 30,
+        "2024-03-12": hour,
+    }
+
+    got := buildBreakdown(buckets)
+
+    want := []bucketResult{
+        {Key: "2024-03-12", Hours: 1, Minutes: 0, Seconds: 0},
+        {Key: "2024-03-13", Hours: 1, Minutes: 30, Seconds: 30},
+    }
+
+    if len(got) != len(want) {
+        t.Fatalf("buildBreakdown returned %d buckets, want %d", len(got), len(want))
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("bucket %d = %+v, want %+v", i, got[i], want[i])
+        }
+    }
+}
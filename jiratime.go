@@ -6,6 +6,7 @@
 package main
 
 import (
+    "context"
     "encoding/json"
     "flag"
     "fmt"
@@ -39,8 +40,18 @@ const hour = 60 * minute
 type config struct {
     Baseurl string
     Username string
-    Userkey string
+    Userkey string       // API token for basic auth, or the token itself when AuthType is "pat"
     Workers int         // (optional in config) To override defWorkers
+    CalendarID string       // Google Calendar ID to reconcile worklogs against
+    GoogleTokenFile string  // File holding an OAuth token for CalendarID
+    CacheDir string         // Directory to store per-user issue/worklog caches in
+    RequestsPerSecond float64 // (optional in config) To override defRequestsPerSecond
+    AuthType string         // "basic" (default), "pat" or "oauth"
+    OAuthClientID string
+    OAuthClientSecret string
+    OAuthRedirectURL string
+    OAuthTokenFile string   // (optional) where the OAuth refresh token is persisted
+    Auth Authenticator `json:"-"` // built from the above once config is loaded
 }
 
 var conf config
@@ -50,6 +61,13 @@ var httpc = &http.Client {
     Timeout : 10 * time.Second,
 }
 
+// initTransport wraps httpc's Transport with rate limiting and backoff,
+// using conf.RequestsPerSecond once config has been loaded
+func initTransport() {
+    httpc.Transport = newRateLimitedTransport(http.DefaultTransport,
+            conf.RequestsPerSecond)
+}
+
 type userinfo struct {
     AccountID string `json:"accountId,omitempty"`
     EmailAddress string `json:"emailAddress"`
@@ -65,8 +83,38 @@ type results struct {
     Minutes uint `json:"minutes"`
     Seconds uint `json:"seconds"`
     TotalSeconds uint `json:"totalSeconds"`
+    Submitted uint `json:"submitted,omitempty"`
+    Skipped uint `json:"skipped,omitempty"`
+    Failed uint `json:"failed,omitempty"`
+    Breakdown []bucketResult `json:"breakdown,omitempty"`
+    CalendarSeconds uint `json:"calendarSeconds,omitempty"`
+    Diff []dayDiff `json:"diff,omitempty"`
     Format  int `json:"-"`
 }
+
+// dayDiff compares time logged to Jira against calendar busy time for a
+// single day, so users can spot days where meetings ate hours they never
+// booked against a ticket
+type dayDiff struct {
+    Day string `json:"day"`
+    LoggedSeconds uint `json:"loggedSeconds"`
+    BusySeconds uint `json:"busySeconds"`
+}
+
+// bucketResult is one row of a grouped breakdown: the time logged for a
+// single day/week/month/issue/project bucket, requested via -group
+type bucketResult struct {
+    Key string `json:"key"`
+    Hours uint `json:"hours"`
+    Minutes uint `json:"minutes"`
+    Seconds uint `json:"seconds"`
+}
+// formatHMS renders a number of seconds as "HhMMm" for compact display in
+// tabular text output
+func formatHMS(seconds uint) string {
+    return fmt.Sprintf("%dh%02dm", seconds/hour, (seconds%hour)/minute)
+}
+
 // displayResults does what the name suggests.  Future versions of this
 // Program will offer alternative output formats
 func displayResults(res *results) {
@@ -76,6 +124,23 @@ func displayResults(res *results) {
         fmt.Printf("%-25s%8s%8s\n%10s - %10s: %8d%8d\n",
                 res.User.DisplayName, "Hours","Minutes",
                 res.Start,res.End,res.Hours,res.Minutes)
+        if res.Submitted+res.Skipped+res.Failed > 0 {
+            fmt.Printf("Submitted: %d  Skipped (already logged): %d  Failed: %d\n",
+                    res.Submitted,res.Skipped,res.Failed)
+        }
+        if len(res.Breakdown) > 0 {
+            fmt.Printf("%-20s%8s%8s\n", "Bucket", "Hours", "Minutes")
+            for _, b := range res.Breakdown {
+                fmt.Printf("%-20s%8d%8d\n", b.Key, b.Hours, b.Minutes)
+            }
+        }
+        if len(res.Diff) > 0 {
+            fmt.Printf("%-12s%10s%10s\n", "Day", "Logged", "Busy")
+            for _, d := range res.Diff {
+                fmt.Printf("%-12s%10s%10s\n", d.Day,
+                        formatHMS(d.LoggedSeconds), formatHMS(d.BusySeconds))
+            }
+        }
     case fmt_json:
         if j, err := json.Marshal(res); err == nil {
             fmt.Println(string(j))
@@ -85,9 +150,18 @@ func displayResults(res *results) {
             fmt.Println(string(j))
         }
     case fmt_csv:
-        fmt.Printf("%s,%s,%s,%s,%s,%d,%d,%d\n",res.User.DisplayName,
+        fmt.Printf("%s,%s,%s,%s,%s,%d,%d,%d,%d,%d,%d\n",res.User.DisplayName,
                 res.User.EmailAddress,res.Start,res.End,res.User.TimeZone,
-                res.Hours,res.Minutes,res.TotalSeconds)
+                res.Hours,res.Minutes,res.TotalSeconds,
+                res.Submitted,res.Skipped,res.Failed)
+        for _, b := range res.Breakdown {
+            fmt.Printf("%s,%s,%s,%d,%d,%d\n",res.User.DisplayName,
+                    res.User.EmailAddress,b.Key,b.Hours,b.Minutes,b.Seconds)
+        }
+        for _, d := range res.Diff {
+            fmt.Printf("%s,%s,%s,%d,%d\n",res.User.DisplayName,
+                    res.User.EmailAddress,d.Day,d.LoggedSeconds,d.BusySeconds)
+        }
     default:
         log.Fatal(fmt.Errorf("Unrecognised display format requested"))
     }
@@ -108,15 +182,19 @@ func getConfig(c *config, filename *string) error {
         return err
     }
 
-    // Check required values present in config
+    // Check required values present in config.  Username/Userkey are not
+    // required for AuthType "oauth", which authenticates via a persisted
+    // OAuth token instead
     if c.Baseurl == "" {
         undef = "baseurl,"
     }
-    if c.Username == "" {
-        undef += "username,"
-    }
-    if c.Userkey == "" {
-        undef += "userkey,"
+    if c.AuthType != "oauth" {
+        if c.Username == "" {
+            undef += "username,"
+        }
+        if c.Userkey == "" {
+            undef += "userkey,"
+        }
     }
 
     if len(undef) == 0 {
@@ -130,15 +208,17 @@ func getConfig(c *config, filename *string) error {
 
 // getCaller retrievs information on the account used to authenticate to
 // the jira APIs
-func getCaller() (*userinfo, error) {
-    
-    req, err := http.NewRequest("GET", conf.Baseurl + "/myself", nil)
+func getCaller(ctx context.Context) (*userinfo, error) {
+
+    req, err := http.NewRequestWithContext(ctx, "GET", conf.Baseurl + "/myself", nil)
     if err != nil {
         return nil,err
     }
 
     req.Header.Add("Accept", "application/json")
-    req.SetBasicAuth(conf.Username,conf.Userkey)
+    if err := conf.Auth.Apply(req); err != nil {
+        return nil,err
+    }
 
     resp,err := httpc.Do(req)
     if  err != nil {
@@ -160,8 +240,8 @@ func getCaller() (*userinfo, error) {
 }
 
 // getUser retrieves the account ID for a given user's email address
-func getUser(user string) (*userinfo, error) {
-    req, err := http.NewRequest("GET", conf.Baseurl + "/user/search", nil)
+func getUser(ctx context.Context, user string) (*userinfo, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", conf.Baseurl + "/user/search", nil)
     if err != nil {
         return nil,err
     }
@@ -173,12 +253,14 @@ func getUser(user string) (*userinfo, error) {
 
     req.Header.Add("Accept", "application/json")
 
-    req.SetBasicAuth(conf.Username,conf.Userkey)
+    if err := conf.Auth.Apply(req); err != nil {
+        return nil,err
+    }
 
     resp,err := httpc.Do(req)
     if  err != nil {
         return nil,err
-    } 
+    }
 
     defer resp.Body.Close()
 
@@ -200,26 +282,45 @@ func getUser(user string) (*userinfo, error) {
     return &u, nil
 }
 
+// issueRef identifies an issue returned by getIDs: the numeric id used to
+// address /issue/{id}/worklog, the human-readable key (e.g. "PROJ-123")
+// used to label and group reports, and the "updated" timestamp getWork
+// uses to decide whether a cached copy of the issue's worklogs is fresh
+type issueRef struct {
+    ID      string
+    Key     string
+    Updated string
+}
+
 // getIDs() retrieves the IDs of all issues which are assigned to the current
-// user and writes them to a channel for consumption by worker goroutines
-func getIDs(user string,start, end time.Time,ids chan<- string) {
+// user and writes them to a channel for consumption by worker goroutines.
+// Errors (including a cancelled ctx) are sent to errs rather than killing
+// the process, so a single failed request doesn't take down the whole run
+func getIDs(ctx context.Context, user string,start, end time.Time,
+        ids chan<- issueRef, errs chan<- error) {
 
     defer  close(ids)
 
-    req, err := http.NewRequest("GET", conf.Baseurl + "/search", nil)
+    req, err := http.NewRequestWithContext(ctx, "GET", conf.Baseurl + "/search", nil)
     if err != nil {
-        log.Fatalf("%s\n",err)
+        errs <- err
+        return
     }
 
     // Note that apparently contrary to the API documentation, worklogs
     // are not returned with jql queries, otherwise we could process them
-    // without requiring further lookups
+    // without requiring further lookups.  "updated" is projected here too
+    // so getWork can tell whether a cached copy of an issue is stale
     j := struct {
         MaxResults uint
         Total uint
         StartAt uint
         Issues [] struct {
             Id string
+            Key string
+            Fields struct {
+                Updated string
+            }
         }
     }{}
 
@@ -240,33 +341,37 @@ func getIDs(user string,start, end time.Time,ids chan<- string) {
         jql += " AND worklogDate <= \"" + end.Format("2006-01-02") + "\""
     }
     p.Set("jql",jql)
-    p.Set("fields","id")
+    p.Set("fields","id,updated")
     p.Set("maxResults","100")       // Max this can be set to in v3 api
 
     req.URL.RawQuery = p.Encode()
 
     req.Header.Add("Accept", "application/json")
 
-    req.SetBasicAuth(conf.Username,conf.Userkey)
+    if err := conf.Auth.Apply(req); err != nil {
+        errs <- err
+        return
+    }
 
     // Only maxResults can be retrieved with each query so if total
     // results > maxResults, need to loop, incrementing startAt each time
     for {
         resp,err := httpc.Do(req)
         if  err != nil {
-            log.Fatalf("Failed to obtain issue list: %v", err)
+            errs <- fmt.Errorf("failed to obtain issue list: %v", err)
+            return
         }
 
         err = json.NewDecoder(resp.Body).Decode(&j)
+        resp.Body.Close()
 
         if err != nil {
-            log.Fatalf("Failed to decode response body: %v\n",err)
+            errs <- fmt.Errorf("failed to decode response body: %v",err)
+            return
         }
 
-        resp.Body.Close()
-
         for _, v := range j.Issues {
-            ids <- v.Id
+            ids <- issueRef{ID: v.Id, Key: v.Key, Updated: v.Fields.Updated}
         }
 
         // End loop if we've retrieved all the results
@@ -281,90 +386,135 @@ func getIDs(user string,start, end time.Time,ids chan<- string) {
 
 }
 
-// getWork() reads issue ID strings from the "ids"  channel, queries for
-// the issue's worklogs, sums the time logged for worklogs which begin
-// between the dates in the start and end time parameters and writes the
-// results to the workTime channel
-func getWork(user string,start,end time.Time,ids <-chan string,
-        workTime chan<- uint, wg *sync.WaitGroup) {
+// workItem describes a single worklog entry which counted towards the
+// report: which issue it was logged against, when it started and how
+// long it lasted.  getWork emits one of these per matching worklog entry
+// rather than a single summed total, so that main can bucket them
+// however the -group flag asks for
+type workItem struct {
+    IssueKey string
+    Started  time.Time
+    Seconds  uint
+}
 
-    // Loop until ids channel is closed (no more issues)
-    for issue := range ids {
-        totalTime := uint(0)
+// fetchIssueWorklogs retrieves every worklog entry logged by user against
+// issue, regardless of date range - callers apply the start/end filter
+// themselves, which lets the result be cached independently of whatever
+// range a particular run asked for
+func fetchIssueWorklogs(ctx context.Context, user, issue string) ([]cachedWorklog, error) {
+
+    j := struct {
+        Worklogs []struct {
+            Author struct {
+                AccountID string
+            }
+            Started string
+            TimeSpentSeconds uint
+        }
+        StartAt uint
+        Total uint
+        MaxResults uint
+    }{}
+
+    var worklogs []cachedWorklog
+
+    p := url.Values{}
+    p.Set("fields","worklogs")
+    p.Set("maxResults","100")
+
+    req, err := http.NewRequestWithContext(ctx, "GET", conf.Baseurl + "/issue/" + issue +
+            "/worklog", nil)
+    if err != nil {
+        return nil,err
+    }
+    req.URL.RawQuery = p.Encode()
+
+    req.Header.Add("Accept", "application/json")
+
+    if err := conf.Auth.Apply(req); err != nil {
+        return nil,err
+    }
+
+    // Retrieve (the api's idea of, not our suggestion) maxResults
+    // results at a time until we've processed all the worklogs
+    for {
+        resp,err := httpc.Do(req)
+        if  err != nil {
+            return nil,err
+        }
+        err = json.NewDecoder(resp.Body).Decode(&j)
+        resp.Body.Close()
 
-        req, err := http.NewRequest("GET", conf.Baseurl + "/issue/" + issue +
-                "/worklog", nil)
         if err != nil {
-            log.Fatal(err)
+            return nil,err
         }
 
-        j := struct {
-            Worklogs []struct {
-                Author struct {
-                    AccountID string
-                }
-                Started string
-                TimeSpentSeconds uint
+        for _, w := range j.Worklogs {
+            if w.Author.AccountID != user {
+                continue
             }
-            StartAt uint
-            Total uint
-            MaxResults uint
-        }{}
+            worklogs = append(worklogs, cachedWorklog{Started: w.Started,
+                    TimeSpentSeconds: w.TimeSpentSeconds})
+        }
 
-        p := url.Values{}
-        p.Set("fields","worklogs")
-        p.Set("maxResults","100")
+        if j.Total - j.StartAt < j.MaxResults {
+            break
+        }
 
+        p.Set("startAt",strconv.FormatUint(uint64(j.StartAt + j.MaxResults),
+                10))
         req.URL.RawQuery = p.Encode()
+    }
 
-        req.Header.Add("Accept", "application/json")
+    return worklogs, nil
+}
 
-        req.SetBasicAuth(conf.Username,conf.Userkey)
+// getWork() reads issueRefs from the "ids" channel and writes a workItem
+// to the workTime channel for every worklog entry which begins between
+// the dates in the start and end time parameters.  If cache is non-nil
+// and an issue's "updated" timestamp matches what's on disk, the cached
+// worklogs are reused instead of re-querying Jira.  A failure fetching one
+// issue's worklogs is sent to errs and that issue is skipped, rather than
+// killing the whole run
+func getWork(ctx context.Context, user string,start,end time.Time,ids <-chan issueRef,
+        cache *worklogCache, workTime chan<- workItem, errs chan<- error,
+        wg *sync.WaitGroup) {
 
-        // Retrieve (the api's idea of, not our suggestion) maxResults
-        // results at a time until we've processed all the worklogs
-        for {
-            resp,err := httpc.Do(req)
-            if  err != nil {
-                log.Fatal(err)
-            }
-            err = json.NewDecoder(resp.Body).Decode(&j)
+    // Loop until ids channel is closed (no more issues)
+    for ref := range ids {
 
+        var worklogs []cachedWorklog
+        var hit bool
+
+        if cache != nil {
+            worklogs, hit = cache.lookup(ref.ID,ref.Updated)
+        }
+
+        if !hit {
+            fetched, err := fetchIssueWorklogs(ctx,user,ref.ID)
             if err != nil {
-                log.Fatal(err)
+                errs <- fmt.Errorf("issue %s: %v",ref.ID,err)
+                continue
             }
-
-            for _, w := range j.Worklogs {
-                started, err := time.Parse("2006-01-02T15:04:05-0700",w.Started)
-                if  err != nil {
-                    log.Printf("jiratime: failed to parse Worklog start: %v\n",
-                            err)
-                    continue
-                }
-                if w.Author.AccountID != user {
-                    continue
-                }
-                if !(start.IsZero() || !started.Before(start)) {
-                    continue
-                }
-                if end.IsZero() || started.Before(end) {
-                    totalTime += w.TimeSpentSeconds
-                }
+            worklogs = fetched
+            if cache != nil {
+                cache.store(ref.ID,ref.Updated,worklogs)
             }
+        }
 
-            // If we're done for this issue, write totalTime to the workTime
-            // channel and continue with the next issue
-            if j.Total - j.StartAt < j.MaxResults {
-                if totalTime != 0 {
-                    workTime<- totalTime
-                }
-                break
+        for _, w := range worklogs {
+            started, err := time.Parse("2006-01-02T15:04:05-0700",w.Started)
+            if  err != nil {
+                log.Printf("jiratime: failed to parse Worklog start: %v\n",err)
+                continue
+            }
+            if !(start.IsZero() || !started.Before(start)) {
+                continue
+            }
+            if end.IsZero() || started.Before(end) {
+                workTime <- workItem{IssueKey: ref.Key, Started: started,
+                        Seconds: w.TimeSpentSeconds}
             }
-
-            // If we're not done, retrieve the next j.MaxResults worklogs
-            p.Set("startAt",strconv.FormatUint(uint64(j.StartAt + j.MaxResults),
-                    10))
-            req.URL.RawQuery = p.Encode()
         }
     }
     wg.Done()
@@ -392,9 +542,29 @@ func main() {
             "Configuration file")
     userp := flag.String("user","","User's email address")
     fmtp := flag.String("format","text","Output format")
+    processp := flag.String("process","",
+            "Post worklog entries read from FILE instead of reporting")
+    groupp := flag.String("group","none",
+            "Group report by day|week|month|issue|project|none")
+    calendarp := flag.Bool("calendar",false,
+            "Reconcile worklogs against Google Calendar "+
+                    "(requires CalendarID/GoogleTokenFile in config)")
+    cachep := flag.Bool("cache",false,
+            "Cache issue/worklog lookups on disk (requires CacheDir in config)")
+    refreshp := flag.Bool("refresh",false,
+            "Force a full cache rebuild (implies -cache)")
+    loginp := flag.Bool("login",false,
+            "Run the OAuth login flow once and save the refresh token "+
+                    "(requires AuthType \"oauth\" in config)")
 
     flag.Parse()
 
+    switch *groupp {
+    case groupNone,groupDay,groupWeek,groupMonth,groupIssue,groupProject:
+    default:
+        log.Fatalf("Unknown -group value: %s\n",*groupp)
+    }
+
     // Determine output format
     switch *fmtp {
     case "text":
@@ -413,9 +583,31 @@ func main() {
         log.Fatalf("Failed to load config: %v\n",err)
     }
 
+    initTransport()
+
+    // ctx is cancelled when main returns, so any in-flight backoff sleeps
+    // or rate-limiter waits are abandoned rather than leaking goroutines
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    if *loginp {
+        if conf.AuthType != "oauth" {
+            log.Fatal("jiratime: -login requires AuthType \"oauth\" in config")
+        }
+        if err := login(ctx,&conf); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
+
+    conf.Auth, err = buildAuthenticator(ctx,&conf)
+    if err != nil {
+        log.Fatal(err)
+    }
+
     // Determine info about caller: Needed for TimeZone as serves as
     // default username for query
-    caller, err := getCaller()
+    caller, err := getCaller(ctx)
     if err != nil {
         log.Fatal(err)
     }
@@ -433,7 +625,7 @@ func main() {
         user = caller
         userLoc = callerLoc
     } else {
-        user, err = getUser(*userp)
+        user, err = getUser(ctx,*userp)
         if err != nil {
             log.Fatal(err)
         }
@@ -446,6 +638,26 @@ func main() {
 
     res.User = user
 
+    if conf.Workers == 0 {
+        conf.Workers = defWorkers
+    }
+
+    // -process takes us down an entirely different path: post worklogs
+    // read from a file instead of reporting on worklogs already in Jira
+    if *processp != "" {
+        entries, err := parseWorklogFile(*processp, userLoc)
+        if err != nil {
+            log.Fatalf("jiratime: failed to parse %s: %v\n", *processp, err)
+        }
+
+        format := res.Format
+        res = processWorklogs(user.AccountID, entries, userLoc, conf.Workers)
+        res.User = user
+        res.Format = format
+        displayResults(res)
+        return
+    }
+
     // Parse end date even if start date parsing fails so we can flag
     // errors if both are wrong
     if *startp != "" {
@@ -482,28 +694,54 @@ func main() {
         }
     }
 
-    if conf.Workers == 0 {
-        conf.Workers = defWorkers
-    }
-
     // Channel for passing issues to workers
-    ids := make(chan string, idbuf)
+    ids := make(chan issueRef, idbuf)
 
     // Channel for passing time spent from workers to main
-    workTime := make(chan uint, conf.Workers)
+    workTime := make(chan workItem, conf.Workers)
 
     // Wait Group used to wait for workers to terminate before program exit
     var wg sync.WaitGroup
 
+    // Load the on-disk cache (if requested) so getWork can skip
+    // re-fetching worklogs for issues that haven't changed since last run
+    var cache *worklogCache
+    if *cachep || *refreshp {
+        if conf.CacheDir == "" {
+            log.Fatal("jiratime: -cache/-refresh requires CacheDir to be " +
+                    "set in config")
+        }
+        cache = loadCache(conf.CacheDir,user.AccountID,*refreshp)
+    }
+
+    // If -calendar was requested, kick off the calendar query in parallel
+    // with getIDs/getWork below; busyCal receives exactly one result
+    var busyCal <-chan calResult
+
+    if *calendarp {
+        if conf.CalendarID == "" || conf.GoogleTokenFile == "" {
+            log.Fatal("jiratime: -calendar requires CalendarID and " +
+                    "GoogleTokenFile to be set in config")
+        }
+
+        var cal calendarSource = &googleCalendar{CalendarID: conf.CalendarID,
+                TokenFile: conf.GoogleTokenFile}
+        busyCal = queryCalendar(cal,start,end,userLoc)
+    }
+
+    // Errors from getIDs/getWork are collected here rather than killing
+    // the process outright; main reports them once the run is done
+    errs := make(chan error, conf.Workers+1)
+
     // Obtain a list of issue IDs where user is the assignee and update time
     // is after the specified start time
 
-    go getIDs(user.AccountID,start,end,ids)
+    go getIDs(ctx,user.AccountID,start,end,ids,errs)
 
     // Start workers
     for i := 0; i < conf.Workers; i++ {
         wg.Add(1)
-        go getWork(user.AccountID,start,end,ids, workTime, &wg)
+        go getWork(ctx,user.AccountID,start,end,ids, cache, workTime, errs, &wg)
     }
 
     // Wait for all works to terminate before closing the workTime channel
@@ -511,12 +749,53 @@ func main() {
     go func() {
         wg.Wait()
         close(workTime)
+        close(errs)
+    }()
+
+    go func() {
+        for e := range errs {
+            log.Printf("jiratime: %v\n",e)
+        }
     }()
 
-    // Accumulate issue time totals until workTime channel closed following
-    // termination of last worker thread
-    for logged := range workTime {
-        res.TotalSeconds += logged
+    // Accumulate issue time totals (and, if requested, per-bucket and
+    // per-day totals) until workTime channel closed following termination
+    // of last worker thread
+    buckets := map[string]uint{}
+    loggedByDay := map[string]uint{}
+    for item := range workTime {
+        res.TotalSeconds += item.Seconds
+        if *groupp != groupNone {
+            buckets[bucketKey(*groupp,item,userLoc)] += item.Seconds
+        }
+        if *calendarp {
+            loggedByDay[bucketKey(groupDay,item,userLoc)] += item.Seconds
+        }
+    }
+
+    if *groupp != groupNone {
+        res.Breakdown = buildBreakdown(buckets)
+    }
+
+    if *calendarp {
+        cal := <-busyCal
+        if cal.err != nil {
+            // Calendar reconciliation is an optional extra on top of the
+            // worklog report; a transient failure here shouldn't discard
+            // a report that's otherwise already been gathered
+            log.Printf("jiratime: calendar reconciliation failed: %v\n",cal.err)
+        } else {
+            for _, seconds := range cal.busy {
+                res.CalendarSeconds += seconds
+            }
+            res.Diff = buildDiff(loggedByDay,cal.busy)
+        }
+    }
+
+    if cache != nil {
+        if err := cache.save(); err != nil {
+            log.Printf("jiratime: failed to save cache: %v\n",err)
+        }
     }
 
     // Add start and end dates to results, formatted as strings
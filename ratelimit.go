@@ -0,0 +1,163 @@
+// ratelimit.go wraps httpc's Transport so that Jira Cloud's 429 rate
+// limit responses (and transient 5xx errors) no longer take the whole
+// process down with a log.Fatal: requests are throttled to a configured
+// rate up front, and any 429/502/503/504 that slips through is retried
+// with exponential backoff and full jitter
+package main
+
+import (
+    "context"
+    "fmt"
+    "math/rand"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// defRequestsPerSecond is used when config doesn't set RequestsPerSecond
+const defRequestsPerSecond = 10
+
+// Backoff tuning: up to maxAttempts retries, doubling each time from
+// backoffBase up to backoffCap, per the "full jitter" algorithm
+const maxAttempts = 5
+const backoffBase = 500 * time.Millisecond
+const backoffCap = 30 * time.Second
+
+// rateLimiter is a simple token bucket shared across every goroutine
+// making requests through httpc, refilled at a constant rate
+type rateLimiter struct {
+    tokens chan struct{}
+}
+
+// newRateLimiter creates a limiter which allows ratePerSecond requests a
+// second, with a small burst allowance equal to that rate
+func newRateLimiter(ratePerSecond float64) *rateLimiter {
+    if ratePerSecond <= 0 {
+        ratePerSecond = defRequestsPerSecond
+    }
+
+    burst := int(ratePerSecond)
+    if burst < 1 {
+        burst = 1
+    }
+
+    rl := &rateLimiter{tokens: make(chan struct{}, burst)}
+
+    // Prime the bucket so the first burst of requests isn't throttled
+    for i := 0; i < burst; i++ {
+        rl.tokens <- struct{}{}
+    }
+
+    interval := time.Duration(float64(time.Second) / ratePerSecond)
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for range ticker.C {
+            select {
+            case rl.tokens <- struct{}{}:
+            default:
+                // Bucket already full; drop this refill
+            }
+        }
+    }()
+
+    return rl
+}
+
+// wait blocks until a token is available or ctx is cancelled
+func (rl *rateLimiter) wait(ctx context.Context) error {
+    select {
+    case <-rl.tokens:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// rateLimitedTransport is an http.RoundTripper that throttles outbound
+// requests through a rateLimiter and retries 429/502/503/504 responses
+// with exponential backoff and full jitter
+type rateLimitedTransport struct {
+    base    http.RoundTripper
+    limiter *rateLimiter
+}
+
+func newRateLimitedTransport(base http.RoundTripper, ratePerSecond float64) *rateLimitedTransport {
+    return &rateLimitedTransport{base: base, limiter: newRateLimiter(ratePerSecond)}
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+    ctx := req.Context()
+
+    for attempt := 0; attempt < maxAttempts; attempt++ {
+        if err := t.limiter.wait(ctx); err != nil {
+            return nil, err
+        }
+
+        // RoundTrip consumes req.Body, so it has to be rewound before
+        // every retry past the first attempt (req.GetBody is set by
+        // http.NewRequest whenever the body can be replayed)
+        if attempt > 0 && req.GetBody != nil {
+            body, err := req.GetBody()
+            if err != nil {
+                return nil, err
+            }
+            req.Body = body
+        }
+
+        resp, err := t.base.RoundTrip(req)
+        if err != nil {
+            return nil, err
+        }
+
+        if !shouldRetry(resp.StatusCode) {
+            return resp, nil
+        }
+
+        wait := retryDelay(resp, attempt)
+        status := resp.Status
+        resp.Body.Close()
+
+        if attempt == maxAttempts-1 {
+            return nil, fmt.Errorf("gave up after %d attempts: %s", maxAttempts, status)
+        }
+
+        select {
+        case <-time.After(wait):
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        }
+    }
+
+    return nil, fmt.Errorf("gave up after %d attempts",maxAttempts)
+}
+
+// shouldRetry reports whether a response warrants a backed-off retry
+func shouldRetry(status int) bool {
+    switch status {
+    case http.StatusTooManyRequests, http.StatusBadGateway,
+            http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+        return true
+    default:
+        return false
+    }
+}
+
+// retryDelay honours a Retry-After header if present, otherwise computes
+// an exponential backoff with full jitter: a random duration between 0
+// and min(backoffCap, backoffBase * 2^attempt)
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+    if ra := resp.Header.Get("Retry-After"); ra != "" {
+        if secs, err := strconv.Atoi(ra); err == nil {
+            return time.Duration(secs) * time.Second
+        }
+    }
+
+    backoff := backoffBase << attempt
+    if backoff > backoffCap || backoff <= 0 {
+        backoff = backoffCap
+    }
+
+    return time.Duration(rand.Int63n(int64(backoff)))
+}
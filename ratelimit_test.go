@@ -0,0 +1,46 @@
+package main
+
+import (
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestShouldRetry(t *testing.T) {
+    tests := []struct {
+        status int
+        want   bool
+    }{
+        {http.StatusOK, false},
+        {http.StatusNotFound, false},
+        {http.StatusTooManyRequests, true},
+        {http.StatusBadGateway, true},
+        {http.StatusServiceUnavailable, true},
+        {http.StatusGatewayTimeout, true},
+    }
+
+    for _, tt := range tests {
+        if got := shouldRetry(tt.status); got != tt.want {
+            t.Errorf("shouldRetry(%d) = %v, want %v", tt.status, got, tt.want)
+        }
+    }
+}
+
+func TestRetryDelayHonoursRetryAfter(t *testing.T) {
+    resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+
+    if got := retryDelay(resp, 0); got != 2*time.Second {
+        t.Errorf("retryDelay with Retry-After = %v, want %v", got, 2*time.Second)
+    }
+}
+
+func TestRetryDelayBacksOffWithinCap(t *testing.T) {
+    resp := &http.Response{Header: http.Header{}}
+
+    for attempt := 0; attempt < maxAttempts; attempt++ {
+        got := retryDelay(resp, attempt)
+        if got < 0 || got > backoffCap {
+            t.Errorf("retryDelay(attempt=%d) = %v, want between 0 and %v", attempt, got, backoffCap)
+        }
+    }
+}
@@ -0,0 +1,346 @@
+// worklog.go adds a "reverse" mode to jiratime: instead of reading time
+// already logged in Jira, it parses a flat text file of worklog entries
+// and posts them to the Jira API, so people who only use jiratime for
+// reporting can also book their time from a file they already maintain.
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// worklogEntry is a single line from a -process file: some amount of time
+// spent on an issue on a given day, with an optional free-text comment
+type worklogEntry struct {
+    Issue   string
+    Day     time.Time
+    Seconds uint
+    Comment string
+}
+
+// submitResult records the outcome of posting (or skipping) one worklogEntry
+type submitResult struct {
+    Entry   worklogEntry
+    Skipped bool
+    Err     error
+}
+
+// dayRe matches the date header lines in a -process file, e.g. 2024-03-12
+var dayRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// entryRe matches worklog lines, e.g. "PROJ-123 1h30m Implement foo"
+var entryRe = regexp.MustCompile(`^(\S+)\s+(\S+)(?:\s+(.*))?$`)
+
+// durationRe matches a single "<n><unit>" component of a duration, e.g. 1h, 30m
+var durationRe = regexp.MustCompile(`(\d+)([hm])`)
+
+// durationTokenRe validates that an entire duration token is made up of
+// "<n>h" and/or "<n>m" components with nothing left over, so a typo like
+// "1h30x" is rejected rather than silently parsed as "1h"
+var durationTokenRe = regexp.MustCompile(`^(?:\d+h)?(?:\d+m)?$`)
+
+// parseWorklogFile reads a plain-text worklog file made up of per-day blocks:
+// a date on its own line, followed by one "ISSUE-KEY duration [comment]"
+// line per entry logged that day.  Day headers are parsed in loc so the
+// resulting worklogEntry.Day is midnight on that calendar day for the
+// user, not for UTC
+func parseWorklogFile(filename string, loc *time.Location) ([]worklogEntry, error) {
+
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    var entries []worklogEntry
+    var day time.Time
+    lineNo := 0
+
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        lineNo++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+
+        if dayRe.MatchString(line) {
+            day, err = time.ParseInLocation("2006-01-02", line, loc)
+            if err != nil {
+                return nil, fmt.Errorf("%s:%d: %v", filename, lineNo, err)
+            }
+            continue
+        }
+
+        if day.IsZero() {
+            return nil, fmt.Errorf("%s:%d: worklog entry before any date header",
+                    filename, lineNo)
+        }
+
+        m := entryRe.FindStringSubmatch(line)
+        if m == nil {
+            return nil, fmt.Errorf("%s:%d: could not parse worklog entry: %q",
+                    filename, lineNo, line)
+        }
+
+        seconds, err := parseDuration(m[2])
+        if err != nil {
+            return nil, fmt.Errorf("%s:%d: %v", filename, lineNo, err)
+        }
+
+        entries = append(entries, worklogEntry{
+            Issue:   m[1],
+            Day:     day,
+            Seconds: seconds,
+            Comment: m[3],
+        })
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    return entries, nil
+}
+
+// parseDuration parses a jiratime-style duration such as "1h30m" or "45m"
+// into a number of seconds
+func parseDuration(s string) (uint, error) {
+    if s == "" || !durationTokenRe.MatchString(s) {
+        return 0, fmt.Errorf("invalid duration: %q", s)
+    }
+
+    matches := durationRe.FindAllStringSubmatch(s, -1)
+
+    var seconds uint
+    for _, m := range matches {
+        n, err := strconv.ParseUint(m[1], 10, 32)
+        if err != nil {
+            return 0, err
+        }
+        switch m[2] {
+        case "h":
+            seconds += uint(n) * hour
+        case "m":
+            seconds += uint(n) * minute
+        }
+    }
+
+    return seconds, nil
+}
+
+// adfComment wraps a plain-text comment in the minimal Atlassian Document
+// Format required by the v3 worklog API
+func adfComment(text string) interface{} {
+    return map[string]interface{}{
+        "type":    "doc",
+        "version": 1,
+        "content": []interface{}{
+            map[string]interface{}{
+                "type": "paragraph",
+                "content": []interface{}{
+                    map[string]interface{}{
+                        "type": "text",
+                        "text": text,
+                    },
+                },
+            },
+        },
+    }
+}
+
+// existingWorklog is the subset of an existing worklog entry we need in
+// order to dedupe against it
+type existingWorklog struct {
+    Author struct {
+        AccountID string
+    }
+    Started          string
+    TimeSpentSeconds uint
+}
+
+// fetchExistingWorklogs retrieves every worklog currently on an issue
+func fetchExistingWorklogs(issue string) ([]existingWorklog, error) {
+
+    j := struct {
+        Worklogs  []existingWorklog
+        StartAt   uint
+        Total     uint
+        MaxResults uint
+    }{}
+
+    var all []existingWorklog
+    startAt := uint(0)
+
+    for {
+        req, err := http.NewRequest("GET",
+                conf.Baseurl+"/issue/"+issue+"/worklog", nil)
+        if err != nil {
+            return nil, err
+        }
+
+        q := req.URL.Query()
+        q.Set("maxResults", "100")
+        q.Set("startAt", strconv.FormatUint(uint64(startAt), 10))
+        req.URL.RawQuery = q.Encode()
+
+        req.Header.Add("Accept", "application/json")
+        if err := conf.Auth.Apply(req); err != nil {
+            return nil, err
+        }
+
+        resp, err := httpc.Do(req)
+        if err != nil {
+            return nil, err
+        }
+
+        err = json.NewDecoder(resp.Body).Decode(&j)
+        resp.Body.Close()
+        if err != nil {
+            return nil, err
+        }
+
+        all = append(all, j.Worklogs...)
+
+        if j.Total-j.StartAt < j.MaxResults {
+            break
+        }
+        startAt = j.StartAt + j.MaxResults
+    }
+
+    return all, nil
+}
+
+// alreadyLogged reports whether a worklog matching this entry (same
+// author, start time and duration) already exists on the issue, so that
+// re-running a -process file is idempotent
+func alreadyLogged(existing []existingWorklog, accountID string, started time.Time,
+        seconds uint) bool {
+
+    for _, w := range existing {
+        if w.Author.AccountID != accountID || w.TimeSpentSeconds != seconds {
+            continue
+        }
+        t, err := time.Parse("2006-01-02T15:04:05-0700", w.Started)
+        if err != nil {
+            continue
+        }
+        if t.Equal(started) {
+            return true
+        }
+    }
+    return false
+}
+
+// postWorklogEntry submits a single worklog entry to Jira, skipping it if
+// an equivalent entry is already present on the issue
+func postWorklogEntry(accountID string, e worklogEntry, loc *time.Location) submitResult {
+
+    started := e.Day.In(loc)
+
+    existing, err := fetchExistingWorklogs(e.Issue)
+    if err != nil {
+        return submitResult{Entry: e, Err: err}
+    }
+
+    if alreadyLogged(existing, accountID, started, e.Seconds) {
+        return submitResult{Entry: e, Skipped: true}
+    }
+
+    body := map[string]interface{}{
+        "started":          started.Format("2006-01-02T15:04:05.000-0700"),
+        "timeSpentSeconds": e.Seconds,
+    }
+    if e.Comment != "" {
+        body["comment"] = adfComment(e.Comment)
+    }
+
+    buf := &bytes.Buffer{}
+    if err := json.NewEncoder(buf).Encode(body); err != nil {
+        return submitResult{Entry: e, Err: err}
+    }
+
+    req, err := http.NewRequest("POST",
+            conf.Baseurl+"/issue/"+e.Issue+"/worklog", buf)
+    if err != nil {
+        return submitResult{Entry: e, Err: err}
+    }
+
+    req.Header.Add("Accept", "application/json")
+    req.Header.Add("Content-Type", "application/json")
+    if err := conf.Auth.Apply(req); err != nil {
+        return submitResult{Entry: e, Err: err}
+    }
+
+    resp, err := httpc.Do(req)
+    if err != nil {
+        return submitResult{Entry: e, Err: err}
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        return submitResult{Entry: e,
+                Err: fmt.Errorf("%s: unexpected status posting worklog: %s",
+                        e.Issue, resp.Status)}
+    }
+
+    return submitResult{Entry: e}
+}
+
+// processWorklogs posts each entry to Jira, streaming submissions through
+// a pool of worker goroutines (the same pattern getWork uses for reads)
+// and returns an aggregate success/failure summary
+func processWorklogs(accountID string, entries []worklogEntry, loc *time.Location,
+        workers int) *results {
+
+    in := make(chan worklogEntry, idbuf)
+    out := make(chan submitResult, workers)
+
+    var wg sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for e := range in {
+                out <- postWorklogEntry(accountID, e, loc)
+            }
+        }()
+    }
+
+    go func() {
+        for _, e := range entries {
+            in <- e
+        }
+        close(in)
+    }()
+
+    go func() {
+        wg.Wait()
+        close(out)
+    }()
+
+    res := &results{}
+    for r := range out {
+        switch {
+        case r.Err != nil:
+            res.Failed++
+            fmt.Fprintf(os.Stderr, "jiratime: %s %s: %v\n",
+                    r.Entry.Issue, r.Entry.Day.Format("2006-01-02"), r.Err)
+        case r.Skipped:
+            res.Skipped++
+        default:
+            res.Submitted++
+        }
+    }
+
+    return res
+}
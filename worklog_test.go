@@ -0,0 +1,91 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func TestParseWorklogFileUsesLocation(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "worklog.txt")
+    contents := "2024-03-12\nPROJ-123 1h30m Implement foo\n"
+    if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    loc, err := time.LoadLocation("America/New_York")
+    if err != nil {
+        t.Skipf("tzdata not available: %v", err)
+    }
+
+    entries, err := parseWorklogFile(path, loc)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(entries) != 1 {
+        t.Fatalf("got %d entries, want 1", len(entries))
+    }
+
+    got := entries[0].Day
+    want := time.Date(2024, 3, 12, 0, 0, 0, 0, loc)
+    if !got.Equal(want) || got.Format("2006-01-02") != "2024-03-12" {
+        t.Errorf("Day = %v, want midnight on 2024-03-12 in %v", got, loc)
+    }
+}
+
+func TestParseDuration(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    uint
+        wantErr bool
+    }{
+        {"1h30m", hour + 30*minute, false},
+        {"45m", 45 * minute, false},
+        {"2h", 2 * hour, false},
+        {"1h30x", 0, true},
+        {"90s", 0, true},
+        {"1d", 0, true},
+        {"", 0, true},
+    }
+
+    for _, tt := range tests {
+        got, err := parseDuration(tt.in)
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("parseDuration(%q) = %d, want error", tt.in, got)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("parseDuration(%q) returned unexpected error: %v", tt.in, err)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("parseDuration(%q) = %d, want %d", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestAlreadyLogged(t *testing.T) {
+    started := time.Date(2024, 3, 12, 9, 0, 0, 0, time.UTC)
+
+    existing := []existingWorklog{
+        {
+            Author:           struct{ AccountID string }{AccountID: "acct-1"},
+            Started:          "2024-03-12T09:00:00+0000",
+            TimeSpentSeconds: hour,
+        },
+    }
+
+    if !alreadyLogged(existing, "acct-1", started, hour) {
+        t.Error("alreadyLogged = false, want true for matching entry")
+    }
+    if alreadyLogged(existing, "acct-2", started, hour) {
+        t.Error("alreadyLogged = true, want false for different account")
+    }
+    if alreadyLogged(existing, "acct-1", started, 2*hour) {
+        t.Error("alreadyLogged = true, want false for different duration")
+    }
+}